@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// SiteConfig describes one page to crawl and how to extract pet ids from it.
+type SiteConfig struct {
+	Site    string            `yaml:"site" json:"site"`
+	Page    string            `yaml:"page" json:"page"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Finder  FinderConfig      `yaml:"finder" json:"finder"`
+}
+
+// FinderConfig picks a finder factory and its tuning parameters. Only the
+// fields relevant to Type need be set; the rest are ignored.
+type FinderConfig struct {
+	// Type selects the factory: "regex", "json" or "css".
+	Type string `yaml:"type" json:"type"`
+
+	// regex: Pattern is applied with FindAllSubmatch; IDGroup/URLGroup pick
+	// the submatch indices (1-based) for the pet id and its url.
+	Pattern  string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	IDGroup  int    `yaml:"id_group,omitempty" json:"id_group,omitempty"`
+	URLGroup int    `yaml:"url_group,omitempty" json:"url_group,omitempty"`
+
+	// json: ArrayPath finds the list of animal objects; IDPath/URLPath are
+	// dot-separated field paths evaluated relative to each array element.
+	ArrayPath string `yaml:"array_path,omitempty" json:"array_path,omitempty"`
+	IDPath    string `yaml:"id_path,omitempty" json:"id_path,omitempty"`
+	URLPath   string `yaml:"url_path,omitempty" json:"url_path,omitempty"`
+
+	// css: Selector picks the anchor/element per animal; IDAttr/URLAttr name
+	// the attributes (or "text" for element text) holding the id and url.
+	Selector string `yaml:"selector,omitempty" json:"selector,omitempty"`
+	IDAttr   string `yaml:"id_attr,omitempty" json:"id_attr,omitempty"`
+	URLAttr  string `yaml:"url_attr,omitempty" json:"url_attr,omitempty"`
+}
+
+// Config is the top-level shape of a config.yaml/config.json file.
+type Config struct {
+	Sites []SiteConfig `yaml:"sites" json:"sites"`
+}
+
+// finderFactories maps a FinderConfig.Type to the code that builds a Finder
+// from it. Registered here so new site schemas don't need a recompile of
+// runCrawl itself.
+var finderFactories = map[string]func(FinderConfig) (Finder, error){
+	"regex": newRegexFinderFromConfig,
+	"json":  newJSONFinderFromConfig,
+	"css":   newCSSFinderFromConfig,
+}
+
+// LoadConfig reads a yaml or json site config, chosen by the file extension.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	cfg := &Config{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported config extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return cfg, nil
+}
+
+// DefaultConfig reproduces the site list paws has always shipped with, as
+// the fallback when no --config is given.
+func DefaultConfig() *Config {
+	seaaca := SiteConfig{
+		Site: "https://www.seaaca.org",
+		Finder: FinderConfig{
+			Type:     "regex",
+			Pattern:  seaacaRex.String(),
+			IDGroup:  2,
+			URLGroup: 1,
+		},
+	}
+	sites := make([]SiteConfig, 0, 6)
+	for page := 0; page < 4; page++ {
+		s := seaaca
+		s.Page = fmt.Sprintf("/adoptions/view-our-animals/?&page=%d", page)
+		sites = append(sites, s)
+	}
+	sites = append(sites, SiteConfig{
+		Site: "https://www.adoptapet.com",
+		Page: "/adoption_rescue/73843-seaaca-southeast-area-animal-control-authority-downey-california",
+		Finder: FinderConfig{
+			Type:     "regex",
+			Pattern:  adoptaRex.String(),
+			IDGroup:  2,
+			URLGroup: 1,
+		},
+	})
+	sites = append(sites, SiteConfig{
+		Site:    "https://www.petfinder.com",
+		Page:    "/search/?page=1&limit[]=40&status=adoptable&distance[]=Anywhere&sort[]=recently_added&shelter_id[]=CA990&include_transportable=true",
+		Headers: petfinderHeaders,
+		Finder: FinderConfig{
+			Type:      "json",
+			ArrayPath: "result.animals",
+			IDPath:    "animal.organization_animal_identifier",
+			URLPath:   "animal.social_sharing.email_url",
+		},
+	})
+	return &Config{Sites: sites}
+}
+
+// BuildCrawls turns a Config into the []*Crawl runCrawl already knows how to run.
+func BuildCrawls(cfg *Config) ([]*Crawl, error) {
+	crawls := make([]*Crawl, 0, len(cfg.Sites))
+	for _, site := range cfg.Sites {
+		factory, ok := finderFactories[site.Finder.Type]
+		if !ok {
+			return nil, fmt.Errorf("%s%s: unknown finder type %q", site.Site, site.Page, site.Finder.Type)
+		}
+		finder, err := factory(site.Finder)
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %w", site.Site, site.Page, err)
+		}
+		crawls = append(crawls, NewCrawl(site.Site, site.Page, site.Headers, finder))
+	}
+	return crawls, nil
+}
+
+// newRegexFinderFromConfig builds the existing regex Finder from a FinderConfig.
+func newRegexFinderFromConfig(cfg FinderConfig) (Finder, error) {
+	re, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern: %w", err)
+	}
+	idGroup, urlGroup := cfg.IDGroup, cfg.URLGroup
+	if numGroups := re.NumSubexp(); idGroup < 1 || idGroup > numGroups || urlGroup < 1 || urlGroup > numGroups {
+		return nil, fmt.Errorf("id_group/url_group must be between 1 and %d (pattern has %d groups), got %d/%d",
+			numGroups, numGroups, idGroup, urlGroup)
+	}
+	return func(data []byte) (AnimalMap, error) {
+		matches := make(AnimalMap)
+		for _, found := range re.FindAllSubmatch(data, -1) {
+			matches[string(found[idGroup])] = string(found[urlGroup])
+		}
+		return matches, nil
+	}, nil
+}
+
+// newJSONFinderFromConfig builds a Finder that walks a json document using
+// dot-separated field paths, generalizing the old hardcoded petFinder schema.
+func newJSONFinderFromConfig(cfg FinderConfig) (Finder, error) {
+	arrayPath, idPath, urlPath := cfg.ArrayPath, cfg.IDPath, cfg.URLPath
+	return func(data []byte) (AnimalMap, error) {
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		animals := make(AnimalMap)
+		array, _ := jsonPath(doc, arrayPath).([]interface{})
+		for _, entry := range array {
+			id, idOk := jsonPath(entry, idPath).(string)
+			url, _ := jsonPath(entry, urlPath).(string)
+			if idOk && id != "" {
+				animals[id] = url
+			}
+		}
+		return animals, nil
+	}, nil
+}
+
+// jsonPath walks a decoded json value following a dot-separated field path.
+func jsonPath(value interface{}, path string) interface{} {
+	for _, field := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[field]
+	}
+	return value
+}
+
+// newCSSFinderFromConfig builds a Finder backed by a goquery selector,
+// replacing brittle per-site regexes like seaacaRex/adoptaRex.
+func newCSSFinderFromConfig(cfg FinderConfig) (Finder, error) {
+	selector, idAttr, urlAttr := cfg.Selector, cfg.IDAttr, cfg.URLAttr
+	return func(data []byte) (AnimalMap, error) {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse html: %w", err)
+		}
+		animals := make(AnimalMap)
+		doc.Find(selector).Each(func(_ int, sel *goquery.Selection) {
+			id := cssValue(sel, idAttr)
+			url := cssValue(sel, urlAttr)
+			if id != "" {
+				animals[id] = url
+			}
+		})
+		return animals, nil
+	}, nil
+}
+
+// cssValue reads either an element attribute or, for "text", its text content.
+func cssValue(sel *goquery.Selection, attr string) string {
+	if attr == "text" {
+		return strings.TrimSpace(sel.Text())
+	}
+	value, _ := sel.Attr(attr)
+	return value
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// defaultUserAgent identifies the paws bot and a contact point for site
+// operators, per good-citizen crawling practice.
+const defaultUserAgent = "pawsbot/1.0 (+https://github.com/kfsone/paws)"
+
+// hostState tracks per-host rate limiting so the seaaca 4-page fan-out (and
+// any other multi-page site) doesn't hammer a host in parallel.
+type hostState struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Fetcher is a shared, polite http.Client wrapper: it honors robots.txt,
+// enforces a minimum per-host delay between requests, and retries
+// 429/5xx responses with exponential backoff.
+type Fetcher struct {
+	UserAgent  string
+	MinDelay   time.Duration
+	MaxRetries int
+
+	client *http.Client
+
+	hostsMu sync.Mutex
+	hosts   map[string]*hostState
+
+	robotsMu sync.Mutex
+	robots   map[string]*robotstxt.RobotsData
+}
+
+// NewFetcher builds a Fetcher with sane defaults; minDelay is the minimum
+// gap enforced between requests to the same host.
+func NewFetcher(userAgent string, minDelay time.Duration) *Fetcher {
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	return &Fetcher{
+		UserAgent:  userAgent,
+		MinDelay:   minDelay,
+		MaxRetries: 3,
+		client:     &http.Client{},
+		hosts:      make(map[string]*hostState),
+		robots:     make(map[string]*robotstxt.RobotsData),
+	}
+}
+
+// Do fetches req, waiting out robots.txt and per-host rate limits first, and
+// retrying 429/5xx responses with backoff honoring Retry-After.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	allowed, err := f.allowed(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("robots: %w", err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("disallowed by robots.txt: %s", req.URL)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		f.throttle(req.URL.Host)
+
+		resp, err = f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if attempt >= f.MaxRetries {
+			return resp, nil
+		}
+		wait := retryDelay(resp, attempt)
+		resp.Body.Close()
+		time.Sleep(wait)
+	}
+}
+
+// throttle blocks until at least MinDelay has passed since the last request
+// issued to host.
+func (f *Fetcher) throttle(host string) {
+	if f.MinDelay <= 0 {
+		return
+	}
+	f.hostsMu.Lock()
+	state, ok := f.hosts[host]
+	if !ok {
+		state = &hostState{}
+		f.hosts[host] = state
+	}
+	f.hostsMu.Unlock()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if wait := time.Until(state.next); wait > 0 {
+		time.Sleep(wait)
+	}
+	state.next = time.Now().Add(f.MinDelay)
+}
+
+// retryDelay computes the exponential-backoff-with-jitter wait before a
+// retry, honoring a Retry-After header when the server sent one.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if ra := resp.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	base := time.Second * time.Duration(1<<attempt)
+	jitter := time.Duration(rand.Int63n(int64(base) / 2))
+	return base + jitter
+}
+
+// allowed fetches (and caches) robots.txt for u's host and reports whether
+// u's path may be crawled by our user agent.
+func (f *Fetcher) allowed(u *url.URL) (bool, error) {
+	f.robotsMu.Lock()
+	robots, cached := f.robots[u.Host]
+	f.robotsMu.Unlock()
+	if !cached {
+		var err error
+		robots, err = f.fetchRobots(u)
+		if err != nil {
+			return false, err
+		}
+		f.robotsMu.Lock()
+		f.robots[u.Host] = robots
+		f.robotsMu.Unlock()
+	}
+	if robots == nil {
+		return true, nil
+	}
+	group := robots.FindGroup(f.UserAgent)
+	return group.Test(u.Path), nil
+}
+
+// fetchRobots retrieves and parses robots.txt for u's host. A missing or
+// unreachable robots.txt is treated as "crawling allowed".
+func (f *Fetcher) fetchRobots(u *url.URL) (*robotstxt.RobotsData, error) {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	resp, err := f.client.Get(robotsURL.String())
+	if err != nil {
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	return robotstxt.FromResponse(resp)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRetryDelayHonorsRetryAfter confirms an explicit Retry-After header
+// overrides the exponential backoff computation entirely.
+func TestRetryDelayHonorsRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if got := retryDelay(resp, 3); got != 7*time.Second {
+		t.Errorf("retryDelay = %v, want 7s", got)
+	}
+}
+
+// TestRetryDelayBacksOffExponentially checks the no-Retry-After path grows
+// with attempt and always includes up to 50% jitter on top of the base.
+func TestRetryDelayBacksOffExponentially(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	for attempt := 0; attempt < 5; attempt++ {
+		base := time.Second * time.Duration(1<<attempt)
+		maxWait := base + base/2
+		for i := 0; i < 20; i++ {
+			got := retryDelay(resp, attempt)
+			if got < base || got > maxWait {
+				t.Fatalf("attempt %d: retryDelay = %v, want in [%v, %v]", attempt, got, base, maxWait)
+			}
+		}
+	}
+}
+
+// TestThrottleEnforcesMinDelay checks that two requests to the same host are
+// separated by at least MinDelay, while a third host is not made to wait on it.
+func TestThrottleEnforcesMinDelay(t *testing.T) {
+	f := NewFetcher("test-agent", 50*time.Millisecond)
+
+	start := time.Now()
+	f.throttle("example.com")
+	f.throttle("example.com")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("second throttle for the same host returned after %v, want >= 50ms", elapsed)
+	}
+
+	start = time.Now()
+	f.throttle("other.com")
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("first throttle for a new host took %v, want ~0", elapsed)
+	}
+}
+
+// TestThrottleDisabledWithoutMinDelay confirms a zero MinDelay never blocks.
+func TestThrottleDisabledWithoutMinDelay(t *testing.T) {
+	f := NewFetcher("test-agent", 0)
+	start := time.Now()
+	f.throttle("example.com")
+	f.throttle("example.com")
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("throttle with MinDelay=0 took %v, want ~0", elapsed)
+	}
+}
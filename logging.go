@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// NewLogger builds the structured logger paws routes all fetch/decode/parse
+// failures through. An empty path logs to stderr; levelName is one of
+// "debug", "info", "warn" or "error" (default "info").
+func NewLogger(path, levelName string) (*slog.Logger, func() error, error) {
+	var out io.Writer = os.Stderr
+	closer := func() error { return nil }
+
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		out = f
+		closer = f.Close
+	}
+
+	level, err := parseLevel(levelName)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+
+	handler := slog.NewTextHandler(out, &slog.HandlerOptions{Level: level})
+	return slog.New(handler), closer, nil
+}
+
+// parseLevel maps a --log-level flag value to a slog.Level.
+func parseLevel(name string) (slog.Level, error) {
+	switch name {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+}
@@ -3,28 +3,37 @@
 package main
 
 import (
+	"bytes"
 	"compress/gzip"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"net/http/httputil"
 	"os"
+	"os/signal"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"text/template"
 	"time"
 )
 
+// version is the paws build version, recorded into WARC warcinfo records.
+const version = "0.1.0"
+
 // AnimalMap is a mapping of petid->url for a set of animals.
 type AnimalMap map[string]string
 
 // Finder is a callback that takes the body of a webpage and extracts a mapping of
-// petid(string) -> peturl(string).
-type Finder func([]byte) AnimalMap
+// petid(string) -> peturl(string). It returns an error if the body could not
+// be parsed at all, rather than silently reporting zero animals found.
+type Finder func([]byte) (AnimalMap, error)
 
 // Crawl represents a url to be crawled and the animals found from doing so.
 type Crawl struct {
@@ -38,6 +47,16 @@ type Crawl struct {
 	Finder  Finder
 	// Animals will be the pet id table returned by Finder.
 	Animals map[string]string
+	// Warc, if set, receives a request/response record pair for this crawl's fetch.
+	Warc *WarcWriter
+	// Replay, if set, supplies the response body instead of hitting the network.
+	Replay *WarcReplay
+	// Fetcher, if set, performs the live fetch; required unless Replay is set.
+	Fetcher *Fetcher
+	// Status, Elapsed and Bytes describe the last Run, for error reporting.
+	Status  int
+	Elapsed time.Duration
+	Bytes   int
 }
 
 // Lazy regular expressions to find the IDs for seaca and adoptapet
@@ -56,8 +75,12 @@ var petfinderHeaders = map[string]string{
 func (c *Crawl) Url() string { return c.Site + c.Page }
 
 // Run will fetch, decode and extract the pet id table for the page of a given crawl.
+// If c.Replay is set the fetch is served from a prior WARC recording instead
+// of the network; if c.Warc is set the live request/response pair is recorded.
 func (c *Crawl) Run() error {
-	client := &http.Client{}
+	started := time.Now()
+	defer func() { c.Elapsed = time.Since(started) }()
+
 	req, err := http.NewRequest("GET", c.Url(), nil)
 	if err != nil {
 		return fmt.Errorf("req: %w", err)
@@ -67,19 +90,54 @@ func (c *Crawl) Run() error {
 			req.Header.Add(hdr, value)
 		}
 	}
-	resp, err := client.Do(req)
+
+	if c.Replay != nil {
+		recorded, ok := c.Replay.Response(c.Url())
+		if !ok {
+			return fmt.Errorf("replay: no recording for %s", c.Url())
+		}
+		body, err := decode(recorded.ContentEncoding, io.NopCloser(bytes.NewReader(recorded.Body)))
+		if err != nil {
+			return fmt.Errorf("decode replay: %w", err)
+		}
+		c.Bytes = len(body)
+		c.Animals, err = c.Finder(body)
+		if err != nil {
+			return fmt.Errorf("find: %w", err)
+		}
+		return nil
+	}
+
+	resp, err := c.Fetcher.Do(req)
 	if err != nil {
 		return fmt.Errorf("get: %w", err)
 	}
+	c.Status = resp.StatusCode
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s: %w", resp.Status, err)
+		resp.Body.Close()
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	raw, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+	c.Bytes = len(raw)
+	if c.Warc != nil {
+		if err := c.Warc.WriteExchange(c.Url(), req, raw); err != nil {
+			return fmt.Errorf("warc: %w", err)
+		}
 	}
+
 	body, err := decode(resp.Header.Get("Content-Encoding"), resp.Body)
 	if err != nil {
 		return fmt.Errorf("read: %w", err)
 	}
 
-	c.Animals = c.Finder(body)
+	c.Animals, err = c.Finder(body)
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
 
 	return nil
 }
@@ -104,82 +162,90 @@ func decode(encoding string, content io.ReadCloser) ([]byte, error) {
 	}
 }
 
-// newRegexFinder is a Finder callback that applies a regex to a crawled page.
-func newRegexFinder(re *regexp.Regexp) Finder {
-	return func(data []byte) AnimalMap {
-		re := re
-		matches := make(AnimalMap)
-		for _, found := range re.FindAllSubmatch(data, -1) {
-			href, id := string(found[1]), string(found[2])
-			matches[id] = href
-		}
-		return matches
-	}
+// shorten is a quick helper to reduce a full sitename down to a prettified link.
+func shorten(sitename string) string {
+	l := strings.Index(sitename, ":/") + 3
+	return `<a href="`+sitename+`" target="_blank">` + sitename[l:] + `</a>`
 }
 
-// PetfinderSchema is a surgical subset of petfinder's animal-query json.
-type PetfinderSchema struct {
-	Result struct {
-		Animals []struct {
-			Animal struct {
-				PetId  string `json:"organization_animal_identifier"`
-				Social struct {
-					Link string `json:"email_url"`
-				} `json:"social_sharing"`
-			} `json:"animal"`
-		} `json:"animals"`
-	} `json:"result"`
+// CrawlError is a single site's fetch/decode failure, surfaced in the
+// report's "Sites with errors" section rather than corrupting stdout.
+type CrawlError struct {
+	Site    string
+	Url     string
+	Error   string
+	Status  int
+	Elapsed time.Duration
 }
 
-// petFinder is a Finder that leverage's PetFinder's json query to get a pet list.
-func petFinder(body []byte) AnimalMap {
-	response := PetfinderSchema{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		panic(err)
-	}
-	animals := make(AnimalMap)
-	for _, animal := range response.Result.Animals {
-		animals[animal.Animal.PetId] = animal.Animal.Social.Link
-	}
-	return animals
+// AnimalInfo is one pet's presence across the ordered list of sites.
+type AnimalInfo struct {
+	Id            string
+	Links         []string
+	PresenceCount int
 }
 
-// shorten is a quick helper to reduce a full sitename down to a prettified link.
-func shorten(sitename string) string {
-	l := strings.Index(sitename, ":/") + 3
-	return `<a href="`+sitename+`" target="_blank">` + sitename[l:] + `</a>`
+// RunResult is everything a single crawl produced, independent of how it's
+// rendered: the HTML report and the JSON dashboard API share this.
+type RunResult struct {
+	Generated string
+	Sites     []string
+	Pets      []AnimalInfo
+	Diff      *Diff
+	Errors    []CrawlError
+}
+
+// runCrawl runs every configured crawl once and renders the HTML report to w.
+func runCrawl(w io.Writer, cfg *Config, warc *WarcWriter, replay *WarcReplay, state *State, fetcher *Fetcher, logger *slog.Logger) error {
+	result, err := doCrawl(cfg, warc, replay, state, fetcher, logger)
+	if err != nil {
+		return err
+	}
+	return renderReport(w, result, logger)
 }
 
-// entry point to run all the calls and aggregate the information.
-func runCrawl(w io.Writer) {
+// doCrawl fetches every configured site, merges the results and diffs them
+// against history, returning the aggregate without rendering anything.
+func doCrawl(cfg *Config, warc *WarcWriter, replay *WarcReplay, state *State, fetcher *Fetcher, logger *slog.Logger) (*RunResult, error) {
 	// record generation time.
 	generated := time.Now().Format("Mon 2006/01/02 15:04:05")
 
-	// table of sites/pages we are going to visit. seaaca is split across three pages.
-	var crawls = []*Crawl{
-		NewCrawl("https://www.seaaca.org", "/adoptions/view-our-animals/?&page=0", nil, newRegexFinder(seaacaRex)),
-		NewCrawl("https://www.seaaca.org", "/adoptions/view-our-animals/?&page=1", nil, newRegexFinder(seaacaRex)),
-		NewCrawl("https://www.seaaca.org", "/adoptions/view-our-animals/?&page=2", nil, newRegexFinder(seaacaRex)),
-		NewCrawl("https://www.seaaca.org", "/adoptions/view-our-animals/?&page=3", nil, newRegexFinder(seaacaRex)),
-		NewCrawl("https://www.adoptapet.com", "/adoption_rescue/73843-seaaca-southeast-area-animal-control-authority-downey-california", nil, newRegexFinder(adoptaRex)),
-		NewCrawl("https://www.petfinder.com", "/search/?page=1&limit[]=40&status=adoptable&distance[]=Anywhere&sort[]=recently_added&shelter_id[]=CA990&include_transportable=true", petfinderHeaders, petFinder),
+	// table of sites/pages we are going to visit, built from the site config
+	// (defaulting to the shelter list paws has always shipped with).
+	crawls, err := BuildCrawls(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
 	}
 
 	// invoke each crawl in its own worker ('go').
+	var mu sync.Mutex
+	var crawlErrors []CrawlError
 	var wg sync.WaitGroup
 	for _, crawl := range crawls {
 		crawl := crawl
+		crawl.Warc = warc
+		crawl.Replay = replay
+		crawl.Fetcher = fetcher
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			if err := crawl.Run(); err != nil {
-				fmt.Printf("ERROR: %s: %s\n", crawl.Url(), err.Error())
+				logger.Error("crawl failed",
+					"site", crawl.Site, "url", crawl.Url(), "status", crawl.Status,
+					"elapsed", crawl.Elapsed, "bytes", crawl.Bytes, "error", err)
+				mu.Lock()
+				crawlErrors = append(crawlErrors, CrawlError{
+					Site: crawl.Site, Url: crawl.Url(), Error: err.Error(),
+					Status: crawl.Status, Elapsed: crawl.Elapsed,
+				})
+				mu.Unlock()
 			}
 		}()
 	}
 
 	// wait for the workers to finish.
 	wg.Wait()
+	sort.Slice(crawlErrors, func(l, r int) bool { return crawlErrors[l].Url < crawlErrors[r].Url })
 
 	// Alpha list of names
 	siteNames := make([]string, 0, len(crawls))
@@ -222,11 +288,6 @@ func runCrawl(w io.Writer) {
 	sort.Strings(petIds)
 
 	// pets by id followed by siteNames-ordered list of hit/miss
-	type AnimalInfo struct {
-		Id string
-		Links []string
-		PresenceCount int
-	}
 	pets := make([]AnimalInfo, 0, len(petSites))
 	for id, petLinks := range petSites {
 		info := AnimalInfo{Id: id, Links: make([]string, len(siteNames))}
@@ -253,35 +314,217 @@ func runCrawl(w io.Writer) {
 		}
 	})
 
-	// the html for the page is stored as a go text/template.
+	// diff this run against persisted history, if a state store is in use.
+	var diff *Diff
+	if state != nil {
+		diff, err = state.RecordRun(siteNames, petSites, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("state: %w", err)
+		}
+	}
+
+	return &RunResult{
+		Generated: generated,
+		Sites:     siteNames,
+		Pets:      pets,
+		Diff:      diff,
+		Errors:    crawlErrors,
+	}, nil
+}
+
+// renderReport writes a RunResult out as the html report, using template.txt.
+func renderReport(w io.Writer, result *RunResult, logger *slog.Logger) error {
 	tpl, err := ioutil.ReadFile("template.txt")
 	if err != nil {
-		panic(err)
+		logger.Error("template read failed", "error", err)
+		return fmt.Errorf("template: %w", err)
+	}
+	pageTemplate, err := template.New("pet-page").Parse(string(tpl))
+	if err != nil {
+		logger.Error("template parse failed", "error", err)
+		return fmt.Errorf("template: %w", err)
 	}
-	var pageTemplate = template.Must(template.New("pet-page").Parse(string(tpl)))
 
-	// generate the html
 	err = pageTemplate.Execute(w, &struct{
 		Generated string
 		Sites []string
 		Pets []AnimalInfo
+		Diff *Diff
+		Errors []CrawlError
 		PoweredBy string
 	} {
-		Generated: generated,
-		Sites: siteNames,
-		Pets: pets,
+		Generated: result.Generated,
+		Sites: result.Sites,
+		Pets: result.Pets,
+		Diff: result.Diff,
+		Errors: result.Errors,
 		PoweredBy: poweredBy(),  // defined in a separate file
 	})
 	if err != nil {
-		panic(err)
+		logger.Error("template execute failed", "error", err)
+		return fmt.Errorf("template: %w", err)
 	}
+	return nil
+}
+
+
+// runHistory implements the "paws history <petid>" subcommand, dumping the
+// recorded sighting timeline for a single pet id from the state store.
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	statePath := fs.String("state", "paws.db", "state database to read")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: paws history [--state=paws.db] <petid>")
+		os.Exit(2)
+	}
+
+	state, err := OpenState(*statePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		os.Exit(1)
+	}
+	defer state.Close()
 
-	// fin.
+	timeline, err := state.History(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		os.Exit(1)
+	}
+	for _, e := range timeline {
+		if e.Seen {
+			fmt.Printf("%s  seen on %s\n", e.Time.Format(time.RFC3339), e.Site)
+		} else {
+			fmt.Printf("%s  missing from this run\n", e.Time.Format(time.RFC3339))
+		}
+	}
 }
 
+// runServe implements "paws serve", running the crawl on a schedule and
+// exposing it (and runtime controls) over http until the process exits.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to serve the dashboard on")
+	interval := fs.Duration("interval", 30*time.Minute, "how often to re-run the crawl")
+	configPath := fs.String("config", "", "site/finder config file (yaml or json); defaults to the built-in site list")
+	statePath := fs.String("state", "paws.db", "persist pet sightings to this state database")
+	userAgent := fs.String("user-agent", "", "User-Agent sent on every fetch; defaults to the paws bot identity")
+	minDelay := fs.Duration("min-delay", 2*time.Second, "minimum delay between requests to the same host")
+	logFile := fs.String("log-file", "", "write structured logs here instead of stderr")
+	logLevel := fs.String("log-level", "info", "log level: debug, info, warn or error")
+	fs.Parse(args)
+
+	logger, closeLog, err := NewLogger(*logFile, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	cfg := DefaultConfig()
+	if *configPath != "" {
+		if cfg, err = LoadConfig(*configPath); err != nil {
+			logger.Error("config load failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	state, err := OpenState(*statePath)
+	if err != nil {
+		logger.Error("state open failed", "error", err)
+		os.Exit(1)
+	}
+	defer state.Close()
+
+	fetcher := NewFetcher(*userAgent, *minDelay)
+	server := NewServer(cfg, nil, nil, state, fetcher, logger, *interval)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		logger.Info("shutting down", "signal", sig)
+		server.Stop()
+		os.Exit(0)
+	}()
+
+	if err := server.Serve(*addr); err != nil {
+		logger.Error("serve failed", "error", err)
+		os.Exit(1)
+	}
+}
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		runHistory(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+
+	warcPath := flag.String("warc", "", "archive every fetched request/response pair to this gzipped WARC file")
+	replayPath := flag.String("replay", "", "read response bodies from this WARC file instead of the network")
+	configPath := flag.String("config", "", "site/finder config file (yaml or json); defaults to the built-in site list")
+	statePath := flag.String("state", "", "persist pet sightings to this state database and report disappearances")
+	userAgent := flag.String("user-agent", "", "User-Agent sent on every fetch; defaults to the paws bot identity")
+	minDelay := flag.Duration("min-delay", 2*time.Second, "minimum delay between requests to the same host")
+	logFile := flag.String("log-file", "", "write structured logs here instead of stderr")
+	logLevel := flag.String("log-level", "info", "log level: debug, info, warn or error")
+	flag.Parse()
+
 	rand.Seed(time.Now().UTC().UnixNano())
-	runCrawl(os.Stdout)
+
+	logger, closeLog, err := NewLogger(*logFile, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	cfg := DefaultConfig()
+	if *configPath != "" {
+		var err error
+		if cfg, err = LoadConfig(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var warc *WarcWriter
+	var replay *WarcReplay
+	if *warcPath != "" {
+		var err error
+		if warc, err = NewWarcWriter(*warcPath, version, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer warc.Close()
+	}
+	if *replayPath != "" {
+		var err error
+		if replay, err = LoadWarcReplay(*replayPath); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var state *State
+	if *statePath != "" {
+		var err error
+		if state, err = OpenState(*statePath); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer state.Close()
+	}
+
+	fetcher := NewFetcher(*userAgent, *minDelay)
+
+	if err := runCrawl(os.Stdout, cfg, warc, replay, state, fetcher, logger); err != nil {
+		logger.Error("run failed", "error", err)
+		os.Exit(1)
+	}
 }
 
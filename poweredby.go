@@ -0,0 +1,6 @@
+package main
+
+// poweredBy returns the attribution snippet embedded in the report footer.
+func poweredBy() string {
+	return `<a href="https://github.com/kfsone/paws" target="_blank">paws</a>`
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRollingReports is how many past runs the dashboard keeps in memory for
+// /history and /api/pets.json. The per-pet timeline at
+// /api/pets/{id}/history is unrelated: it reads the full history straight
+// out of the on-disk State, so it isn't bounded by this.
+const maxRollingReports = 50
+
+// storedReport is one completed run, cached for the dashboard.
+type storedReport struct {
+	When   time.Time
+	HTML   []byte
+	Result *RunResult
+}
+
+// Server runs runCrawl on a schedule and exposes the rolling results over
+// http, alongside runtime controls to trigger, pause and resume the scheduler.
+type Server struct {
+	cfg     *Config
+	warc    *WarcWriter
+	replay  *WarcReplay
+	state   *State
+	fetcher *Fetcher
+	logger  *slog.Logger
+	interval time.Duration
+
+	mu      sync.RWMutex
+	reports []storedReport
+	paused  bool
+	stopCh  chan struct{}
+}
+
+// NewServer builds a Server ready to be started with Serve.
+func NewServer(cfg *Config, warc *WarcWriter, replay *WarcReplay, state *State, fetcher *Fetcher, logger *slog.Logger, interval time.Duration) *Server {
+	return &Server{
+		cfg: cfg, warc: warc, replay: replay, state: state, fetcher: fetcher, logger: logger,
+		interval: interval, stopCh: make(chan struct{}),
+	}
+}
+
+// Serve starts the scheduler loop and blocks serving http on addr.
+func (s *Server) Serve(addr string) error {
+	go s.schedule()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleLatest)
+	mux.HandleFunc("/history", s.handleHistory)
+	mux.HandleFunc("/api/pets.json", s.handlePetsJSON)
+	mux.HandleFunc("/api/pets/", s.handlePetHistory)
+	mux.HandleFunc("/run", s.handleRun)
+	mux.HandleFunc("/pause", s.handlePause)
+	mux.HandleFunc("/resume", s.handleResume)
+
+	s.logger.Info("serving", "addr", addr, "interval", s.interval)
+	return http.ListenAndServe(addr, mux)
+}
+
+// schedule runs a crawl immediately, then every s.interval, until stopped.
+func (s *Server) schedule() {
+	s.runOnce()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.RLock()
+			paused := s.paused
+			s.mu.RUnlock()
+			if !paused {
+				s.runOnce()
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// runOnce performs one crawl and appends it to the rolling report store.
+func (s *Server) runOnce() {
+	result, err := doCrawl(s.cfg, s.warc, s.replay, s.state, s.fetcher, s.logger)
+	if err != nil {
+		s.logger.Error("scheduled run failed", "error", err)
+		return
+	}
+	var html bytes.Buffer
+	if err := renderReport(&html, result, s.logger); err != nil {
+		s.logger.Error("render failed", "error", err)
+		return
+	}
+	s.addReport(storedReport{When: time.Now(), HTML: html.Bytes(), Result: result})
+}
+
+// addReport appends a completed run to the rolling report store, trimming
+// the oldest entries once it grows past maxRollingReports.
+func (s *Server) addReport(report storedReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = append(s.reports, report)
+	if len(s.reports) > maxRollingReports {
+		s.reports = s.reports[len(s.reports)-maxRollingReports:]
+	}
+}
+
+// Stop halts the scheduler loop started by Serve. Safe to call once; callers
+// that want ListenAndServe itself to unwind too still need to arrange that
+// separately, paws's "serve" subcommand just exits after calling this.
+func (s *Server) Stop() {
+	close(s.stopCh)
+}
+
+// latest returns the most recent report, or false if none has run yet.
+func (s *Server) latest() (storedReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.reports) == 0 {
+		return storedReport{}, false
+	}
+	return s.reports[len(s.reports)-1], true
+}
+
+// handleLatest serves GET / with the most recent html report.
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	report, ok := s.latest()
+	if !ok {
+		http.Error(w, "no run has completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(report.HTML)
+}
+
+// handleHistory serves GET /history: timestamps and diff summaries for past runs.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type summary struct {
+		When        time.Time `json:"when"`
+		Pets        int       `json:"pets"`
+		Disappeared int       `json:"disappeared"`
+		Partial     int       `json:"partial"`
+		Errors      int       `json:"errors"`
+	}
+	summaries := make([]summary, 0, len(s.reports))
+	for _, report := range s.reports {
+		s := summary{When: report.When, Pets: len(report.Result.Pets), Errors: len(report.Result.Errors)}
+		if report.Result.Diff != nil {
+			s.Disappeared = len(report.Result.Diff.Disappeared)
+			s.Partial = len(report.Result.Diff.Partial)
+		}
+		summaries = append(summaries, s)
+	}
+	writeJSON(w, summaries)
+}
+
+// handlePetsJSON serves GET /api/pets.json: the latest run's pet table.
+func (s *Server) handlePetsJSON(w http.ResponseWriter, r *http.Request) {
+	report, ok := s.latest()
+	if !ok {
+		http.Error(w, "no run has completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, report.Result)
+}
+
+// handlePetHistory serves GET /api/pets/{id}/history: the persisted
+// sighting timeline for a single pet, backed by the same State.History the
+// "paws history" subcommand uses.
+func (s *Server) handlePetHistory(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/pets/")
+	petID := strings.TrimSuffix(rest, "/history")
+	if petID == "" || petID == rest {
+		http.NotFound(w, r)
+		return
+	}
+	timeline, err := s.state.History(petID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, timeline)
+}
+
+// handleRun serves POST /run, triggering an immediate out-of-schedule crawl.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	go s.runOnce()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handlePause serves POST /pause, halting the scheduled crawl loop.
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleResume serves POST /resume, re-enabling the scheduled crawl loop.
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeJSON encodes v as the http response body, logging (not panicking) on failure.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(w, `{"error": %q}`, err.Error())
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(nil, nil, nil, nil, nil, logger, time.Hour)
+}
+
+// TestServerAddReportTrimsRollingWindow confirms the rolling report store
+// never grows past maxRollingReports and keeps the most recent entries.
+func TestServerAddReportTrimsRollingWindow(t *testing.T) {
+	s := testServer(t)
+	for i := 0; i < maxRollingReports+5; i++ {
+		s.addReport(storedReport{Result: &RunResult{Generated: itoa(i)}})
+	}
+	if len(s.reports) != maxRollingReports {
+		t.Fatalf("len(reports) = %d, want %d", len(s.reports), maxRollingReports)
+	}
+	oldest := s.reports[0].Result.Generated
+	if oldest != itoa(5) {
+		t.Errorf("oldest retained report = %q, want %q", oldest, itoa(5))
+	}
+	newest := s.reports[len(s.reports)-1].Result.Generated
+	if want := itoa(maxRollingReports + 4); newest != want {
+		t.Errorf("newest report = %q, want %q", newest, want)
+	}
+}
+
+// TestServerPauseResume confirms POST /pause and /resume flip s.paused, and
+// that other methods are rejected.
+func TestServerPauseResume(t *testing.T) {
+	s := testServer(t)
+
+	if w := httptest.NewRecorder(); true {
+		s.handlePause(w, httptest.NewRequest(http.MethodGet, "/pause", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET /pause = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.handlePause(w, httptest.NewRequest(http.MethodPost, "/pause", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /pause = %d, want 200", w.Code)
+	}
+	s.mu.RLock()
+	paused := s.paused
+	s.mu.RUnlock()
+	if !paused {
+		t.Fatalf("paused = false after POST /pause")
+	}
+
+	w = httptest.NewRecorder()
+	s.handleResume(w, httptest.NewRequest(http.MethodPost, "/resume", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /resume = %d, want 200", w.Code)
+	}
+	s.mu.RLock()
+	paused = s.paused
+	s.mu.RUnlock()
+	if paused {
+		t.Fatalf("paused = true after POST /resume")
+	}
+}
+
+// TestServerStopClosesStopCh confirms Stop actually unblocks anything
+// selecting on stopCh, which is what schedule's loop relies on to return.
+func TestServerStopClosesStopCh(t *testing.T) {
+	s := testServer(t)
+
+	done := make(chan struct{})
+	go func() {
+		<-s.stopCh
+		close(done)
+	}()
+
+	s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() did not unblock a goroutine waiting on stopCh")
+	}
+}
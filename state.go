@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	petsBucket   = []byte("pets")
+	eventsBucket = []byte("events")
+)
+
+// partialRunThreshold is how many consecutive runs a pet must be seen on
+// some, but not all, sites before it's flagged as a persistent partial listing.
+const partialRunThreshold = 3
+
+// SiteSighting records when a pet was first/last seen on one site.
+type SiteSighting struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	URL       string    `json:"url"`
+}
+
+// PetRecord is the persisted history for a single pet id across all sites.
+type PetRecord struct {
+	PetID       string                  `json:"pet_id"`
+	Sites       map[string]SiteSighting `json:"sites"`
+	PartialRuns int                     `json:"partial_runs"`
+	// Disappeared is set once a pet's absence has been surfaced in a Diff,
+	// so later runs don't keep reporting the same disappearance.
+	Disappeared bool `json:"disappeared,omitempty"`
+}
+
+// event is an append-only timeline entry consumed by "paws history".
+type event struct {
+	Time time.Time `json:"time"`
+	Site string    `json:"site"`
+	Seen bool      `json:"seen"`
+}
+
+// State is the on-disk BoltDB store of pet sightings across runs.
+type State struct {
+	db *bbolt.DB
+}
+
+// OpenState opens (creating if necessary) the state database at path.
+func OpenState(path string) (*State, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open state: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(petsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init state: %w", err)
+	}
+	return &State{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *State) Close() error { return s.db.Close() }
+
+// Diff summarizes how this run's results differ from the persisted history.
+type Diff struct {
+	// Disappeared lists pets that were present last run but absent this run.
+	Disappeared []DisappearedPet
+	// Partial lists pets missing from some sites for partialRunThreshold+ runs.
+	Partial []PartialPet
+}
+
+// DisappearedPet is a pet seen last run but not found in the current one.
+type DisappearedPet struct {
+	PetID    string
+	LastSeen time.Time
+	Sites    []string
+}
+
+// PartialPet is a pet persistently missing from one or more sites.
+type PartialPet struct {
+	PetID   string
+	Present []string
+	Missing []string
+	Runs    int
+}
+
+// RecordRun merges this run's (site -> petid -> url) results into the state
+// store and returns the disappearance/partial-listing diff against history.
+func (s *State) RecordRun(siteNames []string, petSites map[string]AnimalMap, runTime time.Time) (*Diff, error) {
+	diff := &Diff{}
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		pets := tx.Bucket(petsBucket)
+		events := tx.Bucket(eventsBucket)
+
+		seen := make(map[string]bool, len(petSites))
+		for petID, links := range petSites {
+			seen[petID] = true
+
+			record := PetRecord{PetID: petID, Sites: make(map[string]SiteSighting)}
+			if raw := pets.Get([]byte(petID)); raw != nil {
+				if err := json.Unmarshal(raw, &record); err != nil {
+					return fmt.Errorf("decode %s: %w", petID, err)
+				}
+			}
+
+			record.Disappeared = false
+
+			runSites := make(map[string]bool, len(siteNames))
+			for _, site := range siteNames {
+				url, present := links[site]
+				if !present {
+					continue
+				}
+				runSites[site] = true
+
+				sighting, existed := record.Sites[site]
+				if !existed {
+					sighting.FirstSeen = runTime
+				}
+				sighting.LastSeen = runTime
+				sighting.URL = url
+				record.Sites[site] = sighting
+
+				if err := putEvent(events, petID, site, runTime, true); err != nil {
+					return err
+				}
+			}
+
+			// partial-ness is judged against THIS run's site set, not the
+			// historical union in record.Sites (which only ever grows).
+			if len(runSites) < len(siteNames) {
+				record.PartialRuns++
+				if record.PartialRuns >= partialRunThreshold {
+					present, missing := presentAndMissing(runSites, siteNames)
+					diff.Partial = append(diff.Partial, PartialPet{
+						PetID: petID, Present: present, Missing: missing, Runs: record.PartialRuns,
+					})
+				}
+			} else {
+				record.PartialRuns = 0
+			}
+
+			raw, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("encode %s: %w", petID, err)
+			}
+			if err := pets.Put([]byte(petID), raw); err != nil {
+				return err
+			}
+		}
+
+		// anything previously known but absent from this run has disappeared,
+		// but only on the run where it actually drops out of the union of
+		// recently-seen pets: once reported, Disappeared latches so a pet
+		// missing for dozens of runs in a row doesn't flood every Diff.
+		return pets.ForEach(func(key, raw []byte) error {
+			petID := string(key)
+			if seen[petID] {
+				return nil
+			}
+			var record PetRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return fmt.Errorf("decode %s: %w", petID, err)
+			}
+			if err := putEvent(events, petID, "", runTime, false); err != nil {
+				return err
+			}
+			if record.Disappeared {
+				return nil
+			}
+
+			lastSeen := time.Time{}
+			sites := make([]string, 0, len(record.Sites))
+			for site, sighting := range record.Sites {
+				sites = append(sites, site)
+				if sighting.LastSeen.After(lastSeen) {
+					lastSeen = sighting.LastSeen
+				}
+			}
+			diff.Disappeared = append(diff.Disappeared, DisappearedPet{
+				PetID: petID, LastSeen: lastSeen, Sites: sites,
+			})
+
+			record.Disappeared = true
+			raw, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("encode %s: %w", petID, err)
+			}
+			return pets.Put([]byte(petID), raw)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// putEvent appends a timeline entry for petID, keyed so bucket iteration
+// yields entries in chronological order.
+func putEvent(events *bbolt.Bucket, petID, site string, when time.Time, wasSeen bool) error {
+	raw, err := json.Marshal(event{Time: when, Site: site, Seen: wasSeen})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s/%020d", petID, when.UnixNano())
+	return events.Put([]byte(key), raw)
+}
+
+// History returns the recorded timeline of sightings for a single pet id,
+// oldest first, for the "paws history" subcommand.
+func (s *State) History(petID string) ([]event, error) {
+	var timeline []event
+	prefix := []byte(petID + "/")
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(eventsBucket).Cursor()
+		for key, raw := c.Seek(prefix); key != nil && hasPrefix(key, prefix); key, raw = c.Next() {
+			var e event
+			if err := json.Unmarshal(raw, &e); err != nil {
+				return err
+			}
+			timeline = append(timeline, e)
+		}
+		return nil
+	})
+	return timeline, err
+}
+
+func hasPrefix(key, prefix []byte) bool {
+	return len(key) >= len(prefix) && string(key[:len(prefix)]) == string(prefix)
+}
+
+// presentAndMissing splits siteNames into those this run covered and those it didn't.
+func presentAndMissing(runSites map[string]bool, siteNames []string) (present, missing []string) {
+	for _, site := range siteNames {
+		if runSites[site] {
+			present = append(present, site)
+		} else {
+			missing = append(missing, site)
+		}
+	}
+	return present, missing
+}
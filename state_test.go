@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestState(t *testing.T) *State {
+	t.Helper()
+	state, err := OpenState(filepath.Join(t.TempDir(), "paws.db"))
+	if err != nil {
+		t.Fatalf("OpenState: %v", err)
+	}
+	t.Cleanup(func() { state.Close() })
+	return state
+}
+
+// TestRecordRunPartialTracksCurrentRunOnly guards against partial-run
+// detection silently disabling itself once a pet has, at any point in its
+// history, been seen on every site: only the current run's site set should
+// decide whether a run counts as partial.
+func TestRecordRunPartialTracksCurrentRunOnly(t *testing.T) {
+	state := openTestState(t)
+	siteNames := []string{"SiteA", "SiteB"}
+	now := time.Now()
+
+	// run 1: seen on both sites, so the pet's historical Sites map now
+	// contains every site even though later runs only see it on one.
+	if _, err := state.RecordRun(siteNames, map[string]AnimalMap{
+		"p1": {"SiteA": "a1", "SiteB": "b1"},
+	}, now); err != nil {
+		t.Fatalf("run 1: %v", err)
+	}
+
+	// runs 2..threshold+1: only present on SiteA.
+	var diff *Diff
+	var err error
+	for i := 0; i < partialRunThreshold+1; i++ {
+		diff, err = state.RecordRun(siteNames, map[string]AnimalMap{
+			"p1": {"SiteA": "a1"},
+		}, now.Add(time.Duration(i+1)*time.Hour))
+		if err != nil {
+			t.Fatalf("run %d: %v", i+2, err)
+		}
+	}
+
+	found := false
+	for _, p := range diff.Partial {
+		if p.PetID == "p1" {
+			found = true
+			if len(p.Present) != 1 || p.Present[0] != "SiteA" {
+				t.Errorf("Present = %v, want [SiteA]", p.Present)
+			}
+			if len(p.Missing) != 1 || p.Missing[0] != "SiteB" {
+				t.Errorf("Missing = %v, want [SiteB]", p.Missing)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("p1 not reported as a partial listing after %d consecutive partial runs; diff.Partial = %+v",
+			partialRunThreshold+1, diff.Partial)
+	}
+}
+
+// TestRecordRunDisappearance confirms a pet present in one run and absent
+// from the next is surfaced in Diff.Disappeared.
+func TestRecordRunDisappearance(t *testing.T) {
+	state := openTestState(t)
+	siteNames := []string{"SiteA"}
+	now := time.Now()
+
+	if _, err := state.RecordRun(siteNames, map[string]AnimalMap{
+		"p2": {"SiteA": "a2"},
+	}, now); err != nil {
+		t.Fatalf("run 1: %v", err)
+	}
+
+	diff, err := state.RecordRun(siteNames, map[string]AnimalMap{}, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("run 2: %v", err)
+	}
+
+	if len(diff.Disappeared) != 1 || diff.Disappeared[0].PetID != "p2" {
+		t.Fatalf("Disappeared = %+v, want a single entry for p2", diff.Disappeared)
+	}
+}
+
+// TestRecordRunDisappearanceReportedOnce confirms a pet missing for several
+// consecutive runs is only surfaced in Diff.Disappeared on the run where it
+// first goes missing, not on every run after.
+func TestRecordRunDisappearanceReportedOnce(t *testing.T) {
+	state := openTestState(t)
+	siteNames := []string{"SiteA"}
+	now := time.Now()
+
+	if _, err := state.RecordRun(siteNames, map[string]AnimalMap{
+		"p3": {"SiteA": "a3"},
+	}, now); err != nil {
+		t.Fatalf("run 1: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		diff, err := state.RecordRun(siteNames, map[string]AnimalMap{}, now.Add(time.Duration(i+1)*time.Hour))
+		if err != nil {
+			t.Fatalf("run %d: %v", i+2, err)
+		}
+		gotP3 := false
+		for _, p := range diff.Disappeared {
+			if p.PetID == "p3" {
+				gotP3 = true
+			}
+		}
+		if i == 0 && !gotP3 {
+			t.Fatalf("run 2: p3 not reported as disappeared")
+		}
+		if i > 0 && gotP3 {
+			t.Fatalf("run %d: p3 reported as disappeared again; should only surface once", i+2)
+		}
+	}
+}
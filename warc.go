@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// warcDateLayout is the RFC3339 variant WARC-Date expects.
+const warcDateLayout = "2006-01-02T15:04:05Z"
+
+// WarcWriter appends gzip-wrapped WARC 1.1 records to a single warc file, one
+// gzip member per record as the WARC spec requires for seekable archives.
+type WarcWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewWarcWriter creates (or truncates) path and writes the leading warcinfo
+// record describing this paws run.
+func NewWarcWriter(path, version string, started time.Time) (*WarcWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create warc: %w", err)
+	}
+	w := &WarcWriter{file: f}
+	info := fmt.Sprintf("software: paws/%s\r\nrun-started: %s\r\nformat: WARC File Format 1.1\r\n",
+		version, started.UTC().Format(warcDateLayout))
+	if err := w.writeRecord("warcinfo", "", "application/warc-fields", []byte(info)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close closes the underlying warc file.
+func (w *WarcWriter) Close() error { return w.file.Close() }
+
+// WriteExchange appends a warc/request record followed by a warc/response
+// record for a single Crawl fetch, per the WARC 1.1 pairing convention.
+func (w *WarcWriter) WriteExchange(targetURI string, req *http.Request, rawResp []byte) error {
+	reqBytes, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return fmt.Errorf("dump request: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeRecord("request", targetURI, "application/http; msgtype=request", reqBytes); err != nil {
+		return err
+	}
+	return w.writeRecord("response", targetURI, "application/http; msgtype=response", rawResp)
+}
+
+// writeRecord serializes and gzip-compresses a single WARC record.
+func (w *WarcWriter) writeRecord(recordType, targetURI, contentType string, body []byte) error {
+	gz, err := gzip.NewWriterLevel(w.file, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("uuid: %w", err)
+	}
+	fmt.Fprintf(gz, "WARC/1.1\r\n")
+	fmt.Fprintf(gz, "WARC-Type: warc/%s\r\n", recordType)
+	fmt.Fprintf(gz, "WARC-Record-ID: <urn:uuid:%s>\r\n", id.String())
+	fmt.Fprintf(gz, "WARC-Date: %s\r\n", time.Now().UTC().Format(warcDateLayout))
+	if targetURI != "" {
+		fmt.Fprintf(gz, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	fmt.Fprintf(gz, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(gz, "Content-Length: %d\r\n", len(body))
+	fmt.Fprintf(gz, "\r\n")
+	gz.Write(body)
+	fmt.Fprintf(gz, "\r\n\r\n")
+	return gz.Close()
+}
+
+// replayedResponse is a recorded response body plus the headers Crawl.Run
+// needs to process it the same way it would a live fetch.
+type replayedResponse struct {
+	Body            []byte
+	ContentEncoding string
+}
+
+// WarcReplay serves previously-recorded responses from a WARC file in place
+// of live network fetches, keyed by WARC-Target-URI.
+type WarcReplay struct {
+	responses map[string]replayedResponse
+}
+
+// LoadWarcReplay reads every warc/response record out of path and indexes
+// the raw (un-decoded) body, and its Content-Encoding, by target URI.
+func LoadWarcReplay(path string) (*WarcReplay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open warc: %w", err)
+	}
+	defer f.Close()
+
+	replay := &WarcReplay{responses: make(map[string]replayedResponse)}
+	// br is shared across every record so each gzip.Reader below picks up
+	// exactly where the previous one's trailer left off; handing gzip a
+	// fresh bufio.Reader (or the file directly) per iteration loses
+	// whatever that iteration's internal buffer had already read ahead.
+	br := bufio.NewReader(f)
+	for {
+		gz, err := gzip.NewReader(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return replay, nil
+		}
+		gz.Multistream(false)
+		recordType, targetURI, body, err := readRecord(bufio.NewReader(gz))
+		gz.Close()
+		if err != nil {
+			return replay, nil
+		}
+		if recordType == "response" && targetURI != "" {
+			if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(body)), nil); err == nil {
+				respBody, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				replay.responses[targetURI] = replayedResponse{
+					Body:            respBody,
+					ContentEncoding: resp.Header.Get("Content-Encoding"),
+				}
+			}
+		}
+	}
+	return replay, nil
+}
+
+// readRecord parses the WARC-Type/WARC-Target-URI header block and returns
+// the record's Content-Length bytes of payload that follow it.
+func readRecord(r *bufio.Reader) (recordType, targetURI string, body []byte, err error) {
+	if _, err = r.ReadString('\n'); err != nil { // "WARC/1.1"
+		return "", "", nil, err
+	}
+	contentLength := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		switch key {
+		case "WARC-Type":
+			recordType = strings.TrimPrefix(value, "warc/")
+		case "WARC-Target-URI":
+			targetURI = value
+		case "Content-Length":
+			fmt.Sscanf(value, "%d", &contentLength)
+		}
+	}
+	body = make([]byte, contentLength)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return "", "", nil, err
+	}
+	return recordType, targetURI, body, nil
+}
+
+// Response returns the recorded response body (and its Content-Encoding)
+// for a previously-fetched URL, exactly as Crawl.Run would see it live.
+func (r *WarcReplay) Response(targetURI string) (replayedResponse, bool) {
+	resp, ok := r.responses[targetURI]
+	return resp, ok
+}